@@ -0,0 +1,144 @@
+package tfo
+
+import (
+	"net"
+	"sort"
+)
+
+// policyTableEntry is one row of the RFC 6724 default policy table: prefix
+// identifies a block of address space and precedence ranks it relative to
+// the other rows (higher sorts first).
+type policyTableEntry struct {
+	prefix     net.IP
+	prefixLen  int
+	precedence int
+}
+
+// defaultPolicyTable implements the subset of RFC 6724's table (section
+// 2.1) requested for this package: ::1/128 > ::/0 > ::ffff:0:0/96 >
+// 2002::/16 > 2001::/32 > fc00::/7. IPv4 addresses are compared in their
+// ::ffff:0:0/96-mapped form, so a bare IPv4 address matches that row
+// unless it also falls under a more specific one.
+var defaultPolicyTable = []policyTableEntry{
+	{net.ParseIP("::1"), 128, 50},
+	{net.IPv6zero, 0, 40},
+	{net.ParseIP("::ffff:0:0"), 96, 35},
+	{net.ParseIP("2002::"), 16, 30},
+	{net.ParseIP("2001::"), 32, 5},
+	{net.ParseIP("fc00::"), 7, 3},
+}
+
+// precedenceOf returns the precedence of the longest policy-table prefix
+// matching ip, per RFC 6724 §2.1 ("the longest matching prefix is used").
+// Using the maximum precedence across all matches instead would let ::/0
+// (precedence 40) outscore the more specific ::ffff:0:0/96 (precedence 35)
+// for every IPv4-mapped address, which is exactly backwards.
+func precedenceOf(ip net.IP) int {
+	ip16 := ip.To16()
+	precedence := 1
+	longest := -1
+	for _, e := range defaultPolicyTable {
+		if samePrefix(ip16, e.prefix.To16(), e.prefixLen) && e.prefixLen > longest {
+			longest = e.prefixLen
+			precedence = e.precedence
+		}
+	}
+	return precedence
+}
+
+const (
+	scopeLinkLocal = 2
+	scopeGlobal    = 14
+)
+
+func scopeOf(ip net.IP) int {
+	if ip4 := ip.To4(); ip4 != nil {
+		if ip4.IsLoopback() || ip4.IsLinkLocalUnicast() {
+			return scopeLinkLocal
+		}
+		return scopeGlobal
+	}
+	switch {
+	case ip.IsLoopback(), ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return scopeLinkLocal
+	default:
+		return scopeGlobal
+	}
+}
+
+func samePrefix(a, b net.IP, prefixLen int) bool {
+	if prefixLen == 0 {
+		return true
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	n := prefixLen / 8
+	if !a[:n].Equal(b[:n]) {
+		return false
+	}
+	if rem := prefixLen % 8; rem != 0 {
+		mask := byte(0xff << (8 - rem))
+		return a[n]&mask == b[n]&mask
+	}
+	return true
+}
+
+func commonPrefixLen(a, b net.IP) int {
+	a16, b16 := a.To16(), b.To16()
+	if a16 == nil || b16 == nil {
+		return 0
+	}
+	n := 0
+	for i := range a16 {
+		x := a16[i] ^ b16[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+		break
+	}
+	return n
+}
+
+// sortAddrs orders addrs in place per the subset of RFC 6724 destination
+// address selection (section 6) relevant here: prefer a scope matching
+// src, prefer higher policy-table precedence, then prefer the longest
+// matching prefix against src. Rules that require OS route/interface
+// introspection (avoid unusable addresses, avoid deprecated addresses,
+// prefer home addresses) are intentionally not implemented, matching the
+// level of platform awareness tfo-go otherwise relies on.
+func sortAddrs(addrs []net.IP, src net.IP) {
+	srcScope := -1
+	if src != nil {
+		srcScope = scopeOf(src)
+	}
+
+	sort.SliceStable(addrs, func(i, j int) bool {
+		a, b := addrs[i], addrs[j]
+
+		if srcScope >= 0 {
+			aMatch := scopeOf(a) == srcScope
+			bMatch := scopeOf(b) == srcScope
+			if aMatch != bMatch {
+				return aMatch
+			}
+		}
+
+		if pa, pb := precedenceOf(a), precedenceOf(b); pa != pb {
+			return pa > pb
+		}
+
+		if src != nil {
+			if la, lb := commonPrefixLen(a, src), commonPrefixLen(b, src); la != lb {
+				return la > lb
+			}
+		}
+
+		return false
+	})
+}
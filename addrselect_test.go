@@ -0,0 +1,57 @@
+package tfo
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPrecedenceOf(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want int
+	}{
+		{"loopback", "::1", 50},
+		{"native IPv6", "2606:4700:4700::1111", 40},
+		{"IPv4-mapped", "192.0.2.1", 35},
+		{"6to4", "2002:c000:201::1", 30},
+		{"Teredo", "2001:0:4136:e378::1", 5},
+		{"unique local", "fc00::1", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip := net.ParseIP(tt.ip)
+			if ip == nil {
+				t.Fatalf("net.ParseIP(%q) failed", tt.ip)
+			}
+			if got := precedenceOf(ip); got != tt.want {
+				t.Errorf("precedenceOf(%s) = %d, want %d", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortAddrsPrefersIPv6OverIPv4Mapped(t *testing.T) {
+	addrs := []net.IP{
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("2606:4700:4700::1111"),
+	}
+	sortAddrs(addrs, nil)
+
+	if got := addrs[0].String(); got != "2606:4700:4700::1111" {
+		t.Errorf("sortAddrs put %s first, want the native IPv6 address first", got)
+	}
+}
+
+func TestSortAddrsPrefersMatchingScope(t *testing.T) {
+	src := net.ParseIP("203.0.113.10")
+	addrs := []net.IP{
+		net.ParseIP("fe80::1"),
+		net.ParseIP("192.0.2.1"),
+	}
+	sortAddrs(addrs, src)
+
+	if got := addrs[0].String(); got != "192.0.2.1" {
+		t.Errorf("sortAddrs put %s first, want the global-scope address matching src's scope first", got)
+	}
+}
@@ -0,0 +1,419 @@
+package tfo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Dialer wraps net.Dialer to provide TFO-aware dialing.
+type Dialer struct {
+	Dialer net.Dialer
+
+	// FallbackDelay specifies the length of time to wait before spawning a
+	// connection to the next candidate address when racing multiple
+	// addresses returned for the same host, as in RFC 8305 ("Happy
+	// Eyeballs"). If zero, a default delay of 300ms is used. A negative
+	// value disables the stagger: all candidates are started at once.
+	FallbackDelay time.Duration
+}
+
+var errNoSuitableAddress = errors.New("tfo: no suitable address found")
+
+// dialTFOFunc is the dial function raceTFODial uses for each candidate. It
+// exists as a variable, rather than a direct call to dialTFO, purely so
+// tests can substitute controllable fake candidates without opening real
+// sockets.
+var dialTFOFunc = dialTFO
+
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// Dial connects to the address on the named network, which must be "tcp",
+// "tcp4", or "tcp6".
+//
+// Unlike dialing a single resolved *net.TCPAddr, Dial resolves host to the
+// full set of addresses, orders them per RFC 6724, and returns a TFOConn
+// that races dialTFO against that address list with a Happy Eyeballs
+// staggered start the first time it is used (any method but LocalAddr).
+// Whichever candidate connects first wins the race and the rest are
+// closed. When the race is started by the first Write, every candidate
+// that gets started sends that Write's data in its own SYN; the data is
+// not held back from the candidates that later lose.
+func (d *Dialer) Dial(ctx context.Context, network, address string) (TFOConn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := d.Dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ips, err := resolver.LookupIP(ctx, ipNetworkFor(network), host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	portNum, err := resolver.LookupPort(ctx, "tcp", port)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	var laddr *net.TCPAddr
+	if tcpAddr, ok := d.Dialer.LocalAddr.(*net.TCPAddr); ok {
+		laddr = tcpAddr
+	}
+
+	var srcIP net.IP
+	if laddr != nil {
+		srcIP = laddr.IP
+	}
+	sortAddrs(ips, srcIP)
+
+	raddrs := make([]*net.TCPAddr, len(ips))
+	for i, ip := range ips {
+		raddrs[i] = &net.TCPAddr{IP: ip, Port: portNum}
+	}
+
+	return &racingConn{
+		ctx:     ctx,
+		network: network,
+		laddr:   laddr,
+		raddrs:  raddrs,
+		delay:   d.fallbackDelay(),
+	}, nil
+}
+
+func (d *Dialer) fallbackDelay() time.Duration {
+	switch {
+	case d.FallbackDelay > 0:
+		return d.FallbackDelay
+	case d.FallbackDelay < 0:
+		return 0
+	default:
+		return defaultFallbackDelay
+	}
+}
+
+func ipNetworkFor(network string) string {
+	switch network {
+	case "tcp4":
+		return "ip4"
+	case "tcp6":
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// racingConn is the TFOConn returned by Dialer.Dial when more than one
+// candidate address may need to be raced. The race itself is deferred
+// until first use, mirroring how tfoConn defers connectx, so that a first
+// Write's payload can still be delivered in the SYN of whichever candidate
+// wins. Any other method except LocalAddr, if called first, starts the
+// race with no payload (see conn).
+type racingConn struct {
+	mu       sync.Mutex
+	raced    bool
+	selected TFOConn
+	err      error
+
+	ctx     context.Context
+	network string
+	laddr   *net.TCPAddr
+	raddrs  []*net.TCPAddr
+	delay   time.Duration
+}
+
+func (c *racingConn) race(b []byte) (TFOConn, int, error) {
+	conn, n, err := raceTFODial(c.ctx, c.network, c.laddr, c.raddrs, c.delay, b)
+	c.selected, c.err = conn, err
+	c.raced = true
+	return conn, n, err
+}
+
+// conn returns the winning TFOConn, racing for one with no payload if a
+// race has not happened yet. It's used by methods that need a connection
+// to delegate to but carry no data of their own, such as Close or
+// RemoteAddr.
+func (c *racingConn) conn() (TFOConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.raced {
+		return c.selected, c.err
+	}
+	conn, _, err := c.race(nil)
+	return conn, err
+}
+
+func (c *racingConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.raced {
+		conn, err := c.selected, c.err
+		c.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		return conn.Read(b)
+	}
+	conn, _, err := c.race(nil)
+	c.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return conn.Read(b)
+}
+
+// ReadFrom races a connection with no payload, then delegates to the
+// winner's own ReadFrom.
+func (c *racingConn) ReadFrom(r io.Reader) (int64, error) {
+	conn, err := c.conn()
+	if err != nil {
+		return 0, err
+	}
+	return conn.ReadFrom(r)
+}
+
+func (c *racingConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.raced {
+		conn, err := c.selected, c.err
+		c.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		return conn.Write(b)
+	}
+	_, n, err := c.race(b)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *racingConn) Close() error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (c *racingConn) CloseRead() error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.CloseRead()
+}
+
+func (c *racingConn) CloseWrite() error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.CloseWrite()
+}
+
+func (c *racingConn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *racingConn) RemoteAddr() net.Addr {
+	conn, err := c.conn()
+	if err != nil {
+		return nil
+	}
+	return conn.RemoteAddr()
+}
+
+func (c *racingConn) SetDeadline(t time.Time) error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.SetDeadline(t)
+}
+
+func (c *racingConn) SetReadDeadline(t time.Time) error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.SetReadDeadline(t)
+}
+
+func (c *racingConn) SetWriteDeadline(t time.Time) error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.SetWriteDeadline(t)
+}
+
+func (c *racingConn) SetNoDelay(noDelay bool) error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.SetNoDelay(noDelay)
+}
+
+func (c *racingConn) SetKeepAlive(keepalive bool) error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.SetKeepAlive(keepalive)
+}
+
+func (c *racingConn) SetKeepAlivePeriod(d time.Duration) error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.SetKeepAlivePeriod(d)
+}
+
+func (c *racingConn) SetLinger(sec int) error {
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	return conn.SetLinger(sec)
+}
+
+func (c *racingConn) CookieUsed() (bool, error) {
+	conn, err := c.conn()
+	if err != nil {
+		return false, err
+	}
+	return conn.CookieUsed()
+}
+
+type tfoDialResult struct {
+	conn TFOConn
+	n    int
+	err  error
+}
+
+// raceTFODial runs the Happy Eyeballs algorithm (RFC 8305) over raddrs,
+// which must already be sorted by preference (see sortAddrs). Candidates
+// are started one at a time, delay apart; dialTFO and the payload Write
+// happen together for each, so every started candidate sends b in its own
+// SYN, and whichever completes first wins the race. Every other candidate,
+// pending or already connected, is closed.
+//
+// ctx bounds the whole race the way net.Dialer.DialContext bounds a plain
+// dial: cancelling it stops any candidate that hasn't started yet and, for
+// one already in flight, closes its socket to unblock the pending connect
+// or Write.
+func raceTFODial(ctx context.Context, network string, laddr *net.TCPAddr, raddrs []*net.TCPAddr, delay time.Duration, b []byte) (TFOConn, int, error) {
+	if len(raddrs) == 0 {
+		return nil, 0, &net.OpError{Op: "dial", Net: network, Err: errNoSuitableAddress}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, &net.OpError{Op: "dial", Net: network, Addr: raddrs[0], Err: err}
+	}
+
+	results := make(chan tfoDialResult, len(raddrs))
+	done := make(chan struct{})
+	var closeOnce sync.Once
+	var wg sync.WaitGroup
+
+	for i, raddr := range raddrs {
+		i, raddr := i, raddr
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i > 0 {
+				timer := time.NewTimer(time.Duration(i) * delay)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				results <- tfoDialResult{err: ctx.Err()}
+				return
+			default:
+			}
+
+			conn, err := dialTFOFunc(network, laddr, raddr)
+			if err != nil {
+				results <- tfoDialResult{err: err}
+				return
+			}
+
+			// dialTFO's connect and the data Write below can block on the
+			// runtime poller; closing conn unblocks them the same way
+			// net.Dialer.DialContext interrupts a pending connect.
+			stopWatch := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					conn.Close()
+				case <-stopWatch:
+				}
+			}()
+
+			n, err := conn.Write(b)
+			close(stopWatch)
+			if err != nil {
+				conn.Close()
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				}
+				results <- tfoDialResult{err: err}
+				return
+			}
+			results <- tfoDialResult{conn: conn, n: n}
+			closeOnce.Do(func() { close(done) })
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var lastErr error
+	for r := range results {
+		if r.err != nil {
+			lastErr = r.err
+			continue
+		}
+
+		// A winner showed up: return it immediately instead of waiting for
+		// the rest of the field, which is the entire point of Happy
+		// Eyeballs. Whatever else still trickles in - successes included -
+		// is drained and closed in the background.
+		go func() {
+			for rr := range results {
+				if rr.conn != nil {
+					rr.conn.Close()
+				}
+			}
+		}()
+		return r.conn, r.n, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errNoSuitableAddress
+	}
+	return nil, 0, &net.OpError{Op: "dial", Net: network, Addr: raddrs[len(raddrs)-1], Err: lastErr}
+}
@@ -0,0 +1,61 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeTFOConn is a TFOConn stand-in whose Write/Close are exercised by
+// raceTFODial; every other method is unused by these tests and left to
+// panic via the embedded nil interface if ever called.
+type fakeTFOConn struct {
+	TFOConn
+	closed int32
+}
+
+func (c *fakeTFOConn) Write(b []byte) (int, error) { return len(b), nil }
+func (c *fakeTFOConn) Close() error                { atomic.StoreInt32(&c.closed, 1); return nil }
+
+func TestRaceTFODialReturnsOnFirstWinner(t *testing.T) {
+	orig := dialTFOFunc
+	defer func() { dialTFOFunc = orig }()
+
+	slow := &fakeTFOConn{}
+	fast := &fakeTFOConn{}
+
+	dialTFOFunc = func(network string, laddr, raddr *net.TCPAddr) (TFOConn, error) {
+		switch raddr.Port {
+		case 1:
+			time.Sleep(50 * time.Millisecond)
+			return fast, nil
+		case 2:
+			time.Sleep(2 * time.Second)
+			return slow, nil
+		default:
+			t.Fatalf("unexpected candidate port %d", raddr.Port)
+			return nil, nil
+		}
+	}
+
+	raddrs := []*net.TCPAddr{
+		{IP: net.IPv4(127, 0, 0, 1), Port: 1},
+		{IP: net.IPv4(127, 0, 0, 1), Port: 2},
+	}
+
+	start := time.Now()
+	conn, _, err := raceTFODial(context.Background(), "tcp", nil, raddrs, 0, nil)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("raceTFODial: %v", err)
+	}
+	if conn != fast {
+		t.Fatalf("raceTFODial returned %v, want the fast candidate", conn)
+	}
+	if elapsed > 500*time.Millisecond {
+		t.Fatalf("raceTFODial took %v, want it to return as soon as the fast candidate connects (~50ms), not wait for the 2s one", elapsed)
+	}
+}
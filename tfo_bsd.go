@@ -4,6 +4,7 @@
 package tfo
 
 import (
+	"errors"
 	"io"
 	"net"
 	"os"
@@ -13,16 +14,20 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+var errNotTCPListener = errors.New("not a TCP listener")
+var errNotYetConnected = errors.New("tfoConn has not connected yet")
+
 type tfoConn struct {
-	mu        sync.Mutex
-	fd        int
-	f         *os.File
-	connected bool
-	network   string
-	laddr     *net.TCPAddr
-	raddr     *net.TCPAddr
-	lsockaddr unix.Sockaddr
-	rsockaddr unix.Sockaddr
+	mu         sync.Mutex
+	fd         int
+	f          *os.File
+	connected  bool
+	cookieUsed bool
+	network    string
+	laddr      *net.TCPAddr
+	raddr      *net.TCPAddr
+	lsockaddr  unix.Sockaddr
+	rsockaddr  unix.Sockaddr
 }
 
 func setIPv6Only(fd int, family int, ipv6only int) error {
@@ -137,6 +142,114 @@ func dialTFO(network string, laddr, raddr *net.TCPAddr) (TFOConn, error) {
 	}, err
 }
 
+// FileConn returns a copy of the network connection corresponding to the
+// already-open file f, wrapped as a TFOConn. It is the TFO-aware analog of
+// net.FileConn, for sockets handed to the process by a supervisor (systemd
+// socket activation, launchd, inetd-style superservers) rather than dialed
+// by this package. The connection is assumed to already be established, so
+// subsequent writes go through the regular file path instead of connectx.
+// As with net.FileConn, closing the returned TFOConn does not affect f, and
+// closing f does not affect the returned TFOConn.
+func FileConn(f *os.File) (TFOConn, error) {
+	lsockaddr, rsockaddr, network, laddr, raddr, err := getTFOConnAddrs(int(f.Fd()))
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Dup(int(f.Fd()))
+	if err != nil {
+		return nil, wrapSyscallError("dup", err)
+	}
+
+	// Like socket()'s own dialed fds, the duplicated fd must be put into
+	// non-blocking mode before os.NewFile wraps it, or os.NewFile falls
+	// back to a non-pollable file and Read/Write/SetDeadline stop working
+	// as they do on every other tfoConn.
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, wrapSyscallError("setnonblock", err)
+	}
+
+	return &tfoConn{
+		fd:        fd,
+		f:         os.NewFile(uintptr(fd), f.Name()),
+		connected: true,
+		network:   network,
+		laddr:     laddr,
+		raddr:     raddr,
+		lsockaddr: lsockaddr,
+		rsockaddr: rsockaddr,
+	}, nil
+}
+
+// FileListener returns a copy of the network listener corresponding to the
+// already-open file f, with TCP Fast Open enabled on the underlying socket
+// using lc.FastOpenBacklog (or the default backlog if unset), exactly like
+// lc.Listen would for a socket it bound itself. It is the TFO-aware analog
+// of net.FileListener, for sockets handed to the process by a supervisor
+// instead of bound by this package.
+func (lc *ListenConfig) FileListener(f *os.File) (net.Listener, error) {
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, err
+	}
+
+	tcpLn, ok := ln.(*net.TCPListener)
+	if !ok {
+		ln.Close()
+		return nil, &net.OpError{Op: "listen", Net: f.Name(), Err: errNotTCPListener}
+	}
+
+	rawConn, err := tcpLn.SyscallConn()
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	var innerErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		innerErr = SetTFOListenerBacklog(fd, lc.fastOpenBacklog())
+	}); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if innerErr != nil {
+		ln.Close()
+		return nil, innerErr
+	}
+
+	return ln, nil
+}
+
+// getTFOConnAddrs looks up the local and remote socket addresses of fd via
+// getsockname/getpeername, so a tfoConn built around an inherited fd can be
+// populated the same way one built by dialTFO would be.
+func getTFOConnAddrs(fd int) (lsockaddr, rsockaddr unix.Sockaddr, network string, laddr, raddr *net.TCPAddr, err error) {
+	lsockaddr, err = unix.Getsockname(fd)
+	if err != nil {
+		return nil, nil, "", nil, nil, wrapSyscallError("getsockname", err)
+	}
+	rsockaddr, err = unix.Getpeername(fd)
+	if err != nil {
+		return nil, nil, "", nil, nil, wrapSyscallError("getpeername", err)
+	}
+
+	network, laddr = tcpAddrFromSockaddr(lsockaddr)
+	_, raddr = tcpAddrFromSockaddr(rsockaddr)
+	return lsockaddr, rsockaddr, network, laddr, raddr, nil
+}
+
+func tcpAddrFromSockaddr(sa unix.Sockaddr) (network string, addr *net.TCPAddr) {
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		return "tcp4", &net.TCPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+	case *unix.SockaddrInet6:
+		return "tcp6", &net.TCPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+	default:
+		return "tcp", nil
+	}
+}
+
 func (c *tfoConn) Read(b []byte) (int, error) {
 	c.mu.Lock()
 	if !c.connected {
@@ -204,6 +317,20 @@ func (c *tfoConn) CloseWrite() error {
 	return nil
 }
 
+// CookieUsed reports whether the kernel actually shipped data in the SYN
+// using the TFO cookie, as opposed to falling back to a traditional
+// three-way handshake followed by a normal send. It is only meaningful
+// once the connection has completed its first connect; calling it before
+// then returns an error.
+func (c *tfoConn) CookieUsed() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.connected {
+		return false, errNotYetConnected
+	}
+	return c.cookieUsed, nil
+}
+
 func (c *tfoConn) LocalAddr() net.Addr {
 	return c.laddr
 }
@@ -10,8 +10,33 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// defaultFastOpenBacklog is used when ListenConfig.FastOpenBacklog is left
+// at its zero value. 4096 matches common TCP listen backlog sizes used by
+// high-QPS servers, well above the kernel's traditional default of 1
+// cookie-holding SYN.
+const defaultFastOpenBacklog = 4096
+
+func (lc *ListenConfig) fastOpenBacklog() int {
+	if lc.FastOpenBacklog > 0 {
+		return lc.FastOpenBacklog
+	}
+	return defaultFastOpenBacklog
+}
+
 func SetTFOListener(fd uintptr) error {
-	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN, 1)
+	return SetTFOListenerBacklog(fd, defaultFastOpenBacklog)
+}
+
+// SetTFOListenerBacklog enables TCP Fast Open on fd and sets the queue
+// length for pending cookie-holding SYNs to qlen. On Darwin and FreeBSD the
+// kernel only cares whether the value is non-zero, so any qlen greater than
+// zero simply enables TFO; qlen <= 0 falls back to enabling TFO with a
+// queue length of 1.
+func SetTFOListenerBacklog(fd uintptr, qlen int) error {
+	if qlen <= 0 {
+		qlen = 1
+	}
+	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN, qlen)
 }
 
 func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string) (net.Listener, error) {
@@ -28,7 +53,7 @@ func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string)
 		return nil, err
 	}
 	err = rawConn.Control(func(fd uintptr) {
-		innerErr = SetTFOListener(fd)
+		innerErr = SetTFOListenerBacklog(fd, lc.fastOpenBacklog())
 	})
 	if err != nil {
 		ln.Close()
@@ -99,6 +124,12 @@ func (c *tfoConn) connect(b []byte) (n int, err error) {
 		return
 	}
 
+	// connectx reports the number of bytes actually shipped in the SYN. If
+	// we asked it to send a payload and it reports back that none went
+	// out, the kernel fell back to a traditional handshake instead of
+	// using the TFO cookie.
+	c.cookieUsed = len(b) > 0 && n > 0
+
 	err = c.getLocalAddr()
 	return
 }
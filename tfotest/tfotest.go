@@ -0,0 +1,290 @@
+// Package tfotest provides an in-memory, userspace simulation of TFO-aware
+// connections and listeners for use in tests. It lets downstream projects
+// exercise the "data-in-SYN" code path that tfo.Dialer and the real
+// tfoConn.connect implement, on CI runners whose kernels have TCP Fast
+// Open disabled or unavailable (Windows GHA, restricted containers),
+// analogous to how the standard library simulates its fake network on
+// nacl/js.
+package tfotest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	tfo "github.com/database64128/tfo-go"
+)
+
+// Dialer is satisfied by both *tfo.Dialer and the dialer returned by
+// NewInMemoryListener, so test code can depend on an interface instead of
+// committing to a concrete dialer type.
+type Dialer interface {
+	Dial(ctx context.Context, network, address string) (tfo.TFOConn, error)
+}
+
+var errClosed = errors.New("tfotest: use of closed connection")
+var errNotYetConnected = errors.New("tfotest: connection has not connected yet")
+
+var simAddr net.Addr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+
+// simConn is a userspace tfo.TFOConn backed by a pair of cond-var-guarded
+// byte queues, one per direction. The first Read or Write triggers
+// connection establishment: for a Pipe() conn that simply means the other
+// side becomes readable, while for a conn returned by an in-memory
+// Dialer it hands the first Write's payload to the listener atomically
+// with delivering the accepted connection, mirroring how a real TFO SYN
+// carries data before the three-way handshake completes.
+type simConn struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	connected  bool
+	cookieUsed bool
+	onConnect  func(b []byte) // nil for Pipe(); set for dialer-created conns
+	rbuf       []byte
+	closed     bool
+	readDone   bool
+	peer       *simConn
+}
+
+func newSimPair() (*simConn, *simConn) {
+	a := &simConn{}
+	b := &simConn{}
+	a.cond = sync.NewCond(&a.mu)
+	b.cond = sync.NewCond(&b.mu)
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+// Pipe returns two TFOConns already wired to each other, analogous to
+// net.Pipe. Both ends start out connected; there is no simulated dial
+// delay or handshake.
+func Pipe() (tfo.TFOConn, tfo.TFOConn) {
+	a, b := newSimPair()
+	a.connected, b.connected = true, true
+	return a, b
+}
+
+// connectLocked establishes c, delivering b to whatever observes the
+// connection: the peer conn directly, or onConnect for a dialer-created
+// conn that still needs to be handed to a listener. c.mu is held on
+// entry.
+func (c *simConn) connectLocked(b []byte) {
+	c.connected = true
+	c.cookieUsed = len(b) > 0
+	if c.onConnect != nil {
+		c.onConnect(b)
+		return
+	}
+	if len(b) > 0 {
+		c.peer.deliver(b)
+	}
+}
+
+func (c *simConn) deliver(b []byte) {
+	c.mu.Lock()
+	c.rbuf = append(c.rbuf, b...)
+	c.cond.Broadcast()
+	c.mu.Unlock()
+}
+
+func (c *simConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	if !c.connected {
+		c.connectLocked(nil)
+	}
+	for len(c.rbuf) == 0 && !c.readDone && !c.closed {
+		c.cond.Wait()
+	}
+	if len(c.rbuf) == 0 {
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			return 0, errClosed
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	c.mu.Unlock()
+	return n, nil
+}
+
+// ReadFrom mirrors tfoConn.ReadFrom: it establishes the connection first,
+// then relays r to the peer's inbound queue.
+func (c *simConn) ReadFrom(r io.Reader) (int64, error) {
+	c.mu.Lock()
+	if !c.connected {
+		c.connectLocked(nil)
+	}
+	c.mu.Unlock()
+
+	buf := make([]byte, 32*1024)
+	var total int64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			c.peer.deliver(buf[:n])
+			total += int64(n)
+		}
+		if err != nil {
+			if err == io.EOF {
+				return total, nil
+			}
+			return total, err
+		}
+	}
+}
+
+func (c *simConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, errClosed
+	}
+	if !c.connected {
+		c.connectLocked(p)
+		c.mu.Unlock()
+		return len(p), nil
+	}
+	c.mu.Unlock()
+
+	c.peer.deliver(p)
+	return len(p), nil
+}
+
+func (c *simConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	c.peer.mu.Lock()
+	c.peer.readDone = true
+	c.peer.cond.Broadcast()
+	c.peer.mu.Unlock()
+	return nil
+}
+
+func (c *simConn) CloseRead() error {
+	c.mu.Lock()
+	c.readDone = true
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *simConn) CloseWrite() error {
+	c.peer.mu.Lock()
+	c.peer.readDone = true
+	c.peer.cond.Broadcast()
+	c.peer.mu.Unlock()
+	return nil
+}
+
+func (c *simConn) LocalAddr() net.Addr  { return simAddr }
+func (c *simConn) RemoteAddr() net.Addr { return simAddr }
+
+// Deadlines are not simulated: these calls are accepted and ignored, and a
+// Read keeps blocking until data arrives or either end is closed.
+func (c *simConn) SetDeadline(t time.Time) error      { return nil }
+func (c *simConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *simConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// The simulated transport has no real socket to configure; these are
+// accepted and ignored so code written against tfo.TFOConn can run
+// unmodified against tfotest conns.
+func (c *simConn) SetNoDelay(noDelay bool) error            { return nil }
+func (c *simConn) SetKeepAlive(keepalive bool) error        { return nil }
+func (c *simConn) SetKeepAlivePeriod(d time.Duration) error { return nil }
+func (c *simConn) SetLinger(sec int) error                  { return nil }
+
+// CookieUsed reports whether this conn's connection establishment carried
+// a payload, the simulated equivalent of the kernel shipping data in a TFO
+// SYN: true for a dialer-created conn whose first Write had data (and,
+// symmetrically, for the accepted conn that data was delivered to), false
+// for a handshake with no payload or for either end of a Pipe.
+func (c *simConn) CookieUsed() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.connected {
+		return false, errNotYetConnected
+	}
+	return c.cookieUsed, nil
+}
+
+// inMemoryListener is the net.Listener half of NewInMemoryListener's pair:
+// Accept blocks until the paired Dialer's first Write or Read hands it a
+// freshly "accepted" simConn.
+type inMemoryListener struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []*simConn
+	closed  bool
+}
+
+func (ln *inMemoryListener) Accept() (net.Conn, error) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	for len(ln.pending) == 0 && !ln.closed {
+		ln.cond.Wait()
+	}
+	if len(ln.pending) == 0 {
+		return nil, errClosed
+	}
+	c := ln.pending[0]
+	ln.pending = ln.pending[1:]
+	return c, nil
+}
+
+func (ln *inMemoryListener) Close() error {
+	ln.mu.Lock()
+	ln.closed = true
+	ln.cond.Broadcast()
+	ln.mu.Unlock()
+	return nil
+}
+
+func (ln *inMemoryListener) Addr() net.Addr { return simAddr }
+
+func (ln *inMemoryListener) enqueue(c *simConn) {
+	ln.mu.Lock()
+	ln.pending = append(ln.pending, c)
+	ln.cond.Broadcast()
+	ln.mu.Unlock()
+}
+
+type inMemoryDialer struct {
+	ln *inMemoryListener
+}
+
+func (d *inMemoryDialer) Dial(ctx context.Context, network, address string) (tfo.TFOConn, error) {
+	client, server := newSimPair()
+	client.onConnect = func(b []byte) {
+		server.deliver(b)
+		server.mu.Lock()
+		server.connected = true
+		server.cookieUsed = len(b) > 0
+		server.mu.Unlock()
+		d.ln.enqueue(server)
+	}
+	return client, nil
+}
+
+// NewInMemoryListener returns a net.Listener and a paired Dialer that
+// simulate a TFO listener/dialer pair entirely in userspace. Dialing
+// through the returned Dialer and then writing to the resulting TFOConn
+// delivers that first Write's payload to the corresponding Accept'd
+// connection atomically with its acceptance, exercising the same
+// data-in-SYN ordering a real TFO accept would observe.
+func NewInMemoryListener() (net.Listener, Dialer) {
+	ln := &inMemoryListener{}
+	ln.cond = sync.NewCond(&ln.mu)
+	return ln, &inMemoryDialer{ln: ln}
+}
@@ -0,0 +1,82 @@
+package tfotest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPipeRoundTrip(t *testing.T) {
+	a, b := Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	want := []byte("hello from a")
+	if _, err := a.Write(want); err != nil {
+		t.Fatalf("a.Write: %v", err)
+	}
+
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(b, got); err != nil {
+		t.Fatalf("b.Read: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("b read %q, want %q", got, want)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("a.Close: %v", err)
+	}
+	if _, err := b.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("b.Read after a.Close = %v, want io.EOF", err)
+	}
+}
+
+func TestNewInMemoryListenerDataInSYN(t *testing.T) {
+	ln, dialer := NewInMemoryListener()
+	defer ln.Close()
+
+	accepted := make(chan error, 1)
+	var got []byte
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			accepted <- err
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		if err != nil {
+			accepted <- err
+			return
+		}
+		got = buf[:n]
+		accepted <- nil
+	}()
+
+	client, err := dialer.Dial(context.Background(), "tcp", "test:0")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+
+	want := []byte("SYN payload")
+	if _, err := client.Write(want); err != nil {
+		t.Fatalf("client.Write: %v", err)
+	}
+
+	select {
+	case err := <-accepted:
+		if err != nil {
+			t.Fatalf("Accept/Read: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Accept")
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("accepted conn first read %q, want %q delivered atomically with accept", got, want)
+	}
+}